@@ -0,0 +1,79 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// ErrNotLustre is returned when a path resolves to a real, mounted
+// filesystem that isn't Lustre.
+var ErrNotLustre = errors.New("fs: not a Lustre filesystem")
+
+// ErrNotMounted is returned when a path can't be matched to any entry in
+// the mount table at all.
+var ErrNotMounted = errors.New("fs: path is not on a known mount point")
+
+// llSuperMagic is the value statfs(2) reports in f_type for Lustre, per
+// lustre_user.h.
+const llSuperMagic = 0x0BD00BD0
+
+// FSType returns the filesystem type mounted at path, e.g. "lustre",
+// "ext4", "xfs". It statfs's path as a fast path, comparing f_type against
+// LL_SUPER_MAGIC, then falls back to confirming against the mount table by
+// device major:minor for everything else.
+func FSType(path string) (string, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return "", err
+	}
+	if int64(st.Type) == llSuperMagic {
+		return "lustre", nil
+	}
+
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", fmt.Errorf("fs: no stat available for %s", path)
+	}
+	major, minor := uint64(unix.Major(stat.Dev)), uint64(unix.Minor(stat.Dev))
+
+	mounts, err := readMountTable()
+	if err != nil {
+		return "", err
+	}
+
+	var best *mountInfo
+	for _, mi := range mounts {
+		if mi.major != major || mi.minor != minor {
+			continue
+		}
+		if best == nil || len(mi.mountPoint) > len(best.mountPoint) {
+			best = mi
+		}
+	}
+	if best == nil {
+		return "", ErrNotMounted
+	}
+	return best.fsType, nil
+}
+
+// IsLustre reports whether path is on a mounted Lustre filesystem. It
+// returns ErrNotLustre if path resolves to a different, known filesystem
+// type, or ErrNotMounted if it can't be matched to any mount at all.
+func IsLustre(path string) (bool, error) {
+	fsType, err := FSType(path)
+	if err != nil {
+		return false, err
+	}
+	if fsType != "lustre" {
+		return false, ErrNotLustre
+	}
+	return true, nil
+}
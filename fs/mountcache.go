@@ -0,0 +1,118 @@
+package fs
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mountCache is a process-wide, lazily built index of the local Lustre
+// mount table, keyed by device major:minor. It lets MountRoot and
+// MountRelPath resolve a path in O(1) after warmup instead of walking up
+// the directory tree doing an Lstat per level, and it correctly handles
+// bind mounts and submounts since it matches on device rather than on a
+// recursive directory walk.
+type mountCache struct {
+	mu    sync.RWMutex
+	byDev map[uint64][]MountEntry // longest mountpoint first
+	built bool
+
+	watchOnce sync.Once
+}
+
+var gMountCache mountCache
+
+// devKey combines a mount's major:minor into the single key space used by
+// byDev.
+func devKey(major, minor uint64) uint64 {
+	return major<<32 | minor
+}
+
+func (c *mountCache) refresh() error {
+	entries, err := GetLustreMounts()
+	if err != nil {
+		return err
+	}
+
+	byDev := make(map[uint64][]MountEntry, len(entries))
+	for _, e := range entries {
+		k := devKey(e.major, e.minor)
+		byDev[k] = append(byDev[k], e)
+	}
+	for _, group := range byDev {
+		sort.Slice(group, func(i, j int) bool {
+			return len(group[i].RootDir) > len(group[j].RootDir)
+		})
+	}
+
+	c.mu.Lock()
+	c.byDev = byDev
+	c.built = true
+	c.mu.Unlock()
+	return nil
+}
+
+// ensureBuilt builds the cache on first use and starts the background
+// goroutine that keeps it fresh.
+func (c *mountCache) ensureBuilt() error {
+	c.mu.RLock()
+	built := c.built
+	c.mu.RUnlock()
+
+	var err error
+	if !built {
+		err = c.refresh()
+	}
+
+	c.watchOnce.Do(func() {
+		go c.watch()
+	})
+	return err
+}
+
+// watch blocks for the lifetime of the process, rebuilding the cache
+// whenever /proc/self/mountinfo reports a change. If epoll on mountinfo
+// isn't available on this kernel or platform, it falls back to polling
+// on pollFallbackInterval rather than giving up, mirroring MountWatcher.run.
+func (c *mountCache) watch() {
+	useEpoll := true
+	for {
+		if useEpoll {
+			changed, err := watchMountInfo(nil)
+			if err != nil {
+				useEpoll = false
+				continue
+			}
+			if !changed {
+				return
+			}
+		} else {
+			time.Sleep(pollFallbackInterval)
+		}
+		c.refresh()
+	}
+}
+
+// lookup returns the Lustre mount entry whose device matches (major,minor)
+// and whose mount point is a prefix of the cleaned path, along with path
+// relative to that mount point.
+func (c *mountCache) lookup(path string, major, minor uint64) (RootDir, string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, e := range c.byDev[devKey(major, minor)] {
+		mp := string(e.RootDir)
+		prefix := mp
+		if !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		switch {
+		case path == mp:
+			return e.RootDir, "", true
+		case strings.HasPrefix(path, prefix):
+			return e.RootDir, strings.TrimPrefix(path, prefix), true
+		}
+	}
+	return RootDir(""), "", false
+}
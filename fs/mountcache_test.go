@@ -0,0 +1,61 @@
+package fs
+
+import "testing"
+
+func TestMountCacheLookupLongestPrefix(t *testing.T) {
+	const major, minor = 98, 0
+	outer := MountEntry{RootDir: RootDir("/mnt")}
+	inner := MountEntry{RootDir: RootDir("/mnt/scratch")}
+
+	c := &mountCache{
+		byDev: map[uint64][]MountEntry{
+			devKey(major, minor): {inner, outer}, // longest mountpoint first
+		},
+		built: true,
+	}
+
+	root, rel, ok := c.lookup("/mnt/scratch/dir/file", major, minor)
+	if !ok {
+		t.Fatalf("lookup: not found")
+	}
+	if root != inner.RootDir {
+		t.Errorf("root = %q, want %q", root, inner.RootDir)
+	}
+	if rel != "dir/file" {
+		t.Errorf("rel = %q, want dir/file", rel)
+	}
+}
+
+func TestMountCacheLookupExactMountpoint(t *testing.T) {
+	const major, minor = 98, 0
+	entry := MountEntry{RootDir: RootDir("/mnt/scratch")}
+	c := &mountCache{
+		byDev: map[uint64][]MountEntry{devKey(major, minor): {entry}},
+		built: true,
+	}
+
+	root, rel, ok := c.lookup("/mnt/scratch", major, minor)
+	if !ok || root != entry.RootDir || rel != "" {
+		t.Errorf("lookup(mountpoint) = (%q, %q, %v), want (%q, \"\", true)", root, rel, ok, entry.RootDir)
+	}
+}
+
+func TestMountCacheLookupNoMatch(t *testing.T) {
+	c := &mountCache{
+		byDev: map[uint64][]MountEntry{devKey(98, 0): {{RootDir: RootDir("/mnt/scratch")}}},
+		built: true,
+	}
+
+	if _, _, ok := c.lookup("/mnt/scratch", 99, 0); ok {
+		t.Errorf("lookup: expected no match for a different device")
+	}
+	if _, _, ok := c.lookup("/mnt/scratchy", 98, 0); ok {
+		t.Errorf("lookup: expected no match for a sibling directory sharing a prefix")
+	}
+}
+
+func TestDevKeyDistinct(t *testing.T) {
+	if devKey(1, 2) == devKey(2, 1) {
+		t.Errorf("devKey(1,2) collided with devKey(2,1)")
+	}
+}
@@ -0,0 +1,245 @@
+package fs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+
+	"github.intel.com/hpdd/lustre/lnet"
+)
+
+// MountEntry describes a single Lustre client mounted on the local host, as
+// found in /proc/self/mountinfo.
+type MountEntry struct {
+	RootDir
+
+	// FsName is the Lustre filesystem name (e.g. "scratch").
+	FsName string
+
+	// MgsNids lists the MGS servers used to mount this filesystem, parsed
+	// from the mount source. Each element is one server's failover NIDs,
+	// in the order they appear in the mount source.
+	MgsNids [][]lnet.Nid
+
+	// Options are the mount options reported for this entry.
+	Options []string
+
+	// ClientID is the local client identifier for this mount, as returned
+	// by MountID.
+	ClientID string
+
+	major, minor uint64
+}
+
+// mountInfo is a single parsed line of /proc/self/mountinfo (or, as a
+// fallback, /proc/mounts).
+type mountInfo struct {
+	mountPoint string
+	fsType     string
+	source     string
+	options    []string
+	major      uint64
+	minor      uint64
+}
+
+// mountInfoMinFields is the minimum number of whitespace separated fields in
+// a mountinfo line, per Documentation/filesystems/proc.txt.
+const mountInfoMinFields = 10
+
+// parseMountInfoLine parses a single line of /proc/self/mountinfo, e.g.:
+//
+//	36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+//
+// Fields 7 onward are zero or more optional fields, terminated by a literal
+// "-" separator ahead of the fstype, source, and super options.
+func parseMountInfoLine(line string) (*mountInfo, error) {
+	fields := strings.Fields(line)
+	if len(fields) < mountInfoMinFields {
+		return nil, fmt.Errorf("mountinfo: wrong number of fields (%d): %q", len(fields), line)
+	}
+
+	mm := strings.SplitN(fields[2], ":", 2)
+	if len(mm) != 2 {
+		return nil, fmt.Errorf("mountinfo: bad major:minor %q: %q", fields[2], line)
+	}
+	major, err := strconv.ParseUint(mm[0], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("mountinfo: bad major %q: %q", mm[0], line)
+	}
+	minor, err := strconv.ParseUint(mm[1], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("mountinfo: bad minor %q: %q", mm[1], line)
+	}
+
+	sep := -1
+	for i := 6; i < len(fields); i++ {
+		if fields[i] == "-" {
+			sep = i
+			break
+		}
+	}
+	if sep == -1 || len(fields) < sep+3 {
+		return nil, fmt.Errorf("mountinfo: missing \"-\" separator: %q", line)
+	}
+
+	mi := &mountInfo{
+		mountPoint: fields[4],
+		fsType:     fields[sep+1],
+		source:     fields[sep+2],
+		major:      major,
+		minor:      minor,
+	}
+	if len(fields) > sep+3 {
+		mi.options = strings.Split(fields[sep+3], ",")
+	}
+	return mi, nil
+}
+
+// parseProcMountsLine parses a single line of the simpler /proc/mounts
+// format: "device mountpoint fstype options dump pass". Device numbers
+// aren't available here, so callers that need them must fall back to
+// Lstat.
+func parseProcMountsLine(line string) (*mountInfo, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("mounts: wrong number of fields (%d): %q", len(fields), line)
+	}
+	return &mountInfo{
+		source:     fields[0],
+		mountPoint: fields[1],
+		fsType:     fields[2],
+		options:    strings.Split(fields[3], ","),
+	}, nil
+}
+
+// maxMountInfoRetries bounds the number of times readStableFile will re-read
+// a mount table file looking for two consecutive, byte-identical reads. The
+// mount table can change while we are reading it, and a line-by-line parse
+// of a single read can't detect that it observed a torn snapshot, so we
+// instead read the whole file repeatedly until it stops changing underneath
+// us; this mirrors the approach taken by Kubernetes' mount_linux.go and
+// Docker's mount.GetMounts.
+const maxMountInfoRetries = 3
+
+// readStableFile reads path repeatedly, up to tries times, until two
+// consecutive reads return identical content. It returns the last read
+// either way, since a file that won't settle is still the best information
+// we have.
+func readStableFile(path string, tries int) ([]byte, error) {
+	prev, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for i := 1; i < tries; i++ {
+		cur, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(prev, cur) {
+			return cur, nil
+		}
+		prev = cur
+	}
+	return prev, nil
+}
+
+func parseMountInfoBytes(raw []byte, parseLine func(string) (*mountInfo, error)) ([]*mountInfo, error) {
+	var mounts []*mountInfo
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		mi, err := parseLine(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		mounts = append(mounts, mi)
+	}
+	return mounts, scanner.Err()
+}
+
+// readMountTable returns every entry in the kernel's mount table, reading
+// /proc/self/mountinfo (with retries until the read is stable) and falling
+// back to /proc/mounts if mountinfo can't be read at all.
+func readMountTable() ([]*mountInfo, error) {
+	raw, err := readStableFile("/proc/self/mountinfo", maxMountInfoRetries)
+	if err == nil {
+		var mounts []*mountInfo
+		if mounts, err = parseMountInfoBytes(raw, parseMountInfoLine); err == nil {
+			return mounts, nil
+		}
+	}
+
+	if raw, ferr := os.ReadFile("/proc/mounts"); ferr == nil {
+		if mounts, perr := parseMountInfoBytes(raw, parseProcMountsLine); perr == nil {
+			fillDeviceNumbers(mounts)
+			return mounts, nil
+		}
+	}
+
+	return nil, fmt.Errorf("reading mount table: %v", err)
+}
+
+// fillDeviceNumbers backfills major/minor for entries parsed from
+// /proc/mounts, which doesn't carry device numbers itself. It Lstats each
+// mountpoint directly; entries whose mountpoint can no longer be stat'd are
+// left with a zero major:minor rather than failing the whole table.
+func fillDeviceNumbers(mounts []*mountInfo) {
+	for _, mi := range mounts {
+		fi, err := os.Lstat(mi.mountPoint)
+		if err != nil {
+			continue
+		}
+		stat, ok := fi.Sys().(*syscall.Stat_t)
+		if !ok {
+			continue
+		}
+		mi.major, mi.minor = uint64(unix.Major(stat.Dev)), uint64(unix.Minor(stat.Dev))
+	}
+}
+
+// GetLustreMounts returns every Lustre filesystem currently mounted on the
+// local host. It discovers them by parsing /proc/self/mountinfo (falling
+// back to /proc/mounts), filtering for entries whose filesystem type is
+// "lustre" and de-duplicating by (major:minor, mountpoint).
+func GetLustreMounts() ([]MountEntry, error) {
+	mounts, err := readMountTable()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var entries []MountEntry
+	for _, mi := range mounts {
+		if mi.fsType != "lustre" {
+			continue
+		}
+		key := fmt.Sprintf("%d:%d:%s", mi.major, mi.minor, mi.mountPoint)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		nids, fsName, _ := lnet.ParseMGSSpec(mi.source)
+		entry := MountEntry{
+			RootDir: RootDir(mi.mountPoint),
+			FsName:  fsName,
+			MgsNids: nids,
+			Options: mi.options,
+			major:   mi.major,
+			minor:   mi.minor,
+		}
+		if id, err := MountID(mi.mountPoint); err == nil {
+			entry.ClientID = id.ClientID
+			if entry.FsName == "" {
+				entry.FsName = id.FsName
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
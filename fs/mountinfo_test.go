@@ -0,0 +1,71 @@
+package fs
+
+import "testing"
+
+func TestParseMountInfoLine(t *testing.T) {
+	line := `36 35 98:0 / /mnt/scratch rw,noatime master:1 - lustre 10.0.0.1@tcp0:/scratch rw,lazystatfs`
+	mi, err := parseMountInfoLine(line)
+	if err != nil {
+		t.Fatalf("parseMountInfoLine: %v", err)
+	}
+	if mi.mountPoint != "/mnt/scratch" {
+		t.Errorf("mountPoint = %q, want /mnt/scratch", mi.mountPoint)
+	}
+	if mi.fsType != "lustre" {
+		t.Errorf("fsType = %q, want lustre", mi.fsType)
+	}
+	if mi.source != "10.0.0.1@tcp0:/scratch" {
+		t.Errorf("source = %q, want 10.0.0.1@tcp0:/scratch", mi.source)
+	}
+	if mi.major != 98 || mi.minor != 0 {
+		t.Errorf("major:minor = %d:%d, want 98:0", mi.major, mi.minor)
+	}
+	if len(mi.options) != 2 || mi.options[0] != "rw" || mi.options[1] != "lazystatfs" {
+		t.Errorf("options = %v, want [rw lazystatfs]", mi.options)
+	}
+}
+
+func TestParseMountInfoLineNoOptionalFields(t *testing.T) {
+	line := `15 20 8:1 / / rw - ext4 /dev/sda1 rw,relatime`
+	mi, err := parseMountInfoLine(line)
+	if err != nil {
+		t.Fatalf("parseMountInfoLine: %v", err)
+	}
+	if mi.mountPoint != "/" || mi.fsType != "ext4" || mi.major != 8 || mi.minor != 1 {
+		t.Errorf("unexpected result: %+v", mi)
+	}
+}
+
+func TestParseMountInfoLineErrors(t *testing.T) {
+	cases := []string{
+		"",
+		"36 35 98:0 / /mnt rw", // too few fields
+		"36 35 980 / /mnt rw master:1 - lustre src opts", // bad major:minor
+		"36 35 98:0 / /mnt rw master:1 lustre src opts",  // missing "-" separator
+	}
+	for _, line := range cases {
+		if _, err := parseMountInfoLine(line); err == nil {
+			t.Errorf("parseMountInfoLine(%q): expected error, got nil", line)
+		}
+	}
+}
+
+func TestParseProcMountsLine(t *testing.T) {
+	line := "10.0.0.1@tcp0:/scratch /mnt/scratch lustre rw,lazystatfs 0 0"
+	mi, err := parseProcMountsLine(line)
+	if err != nil {
+		t.Fatalf("parseProcMountsLine: %v", err)
+	}
+	if mi.source != "10.0.0.1@tcp0:/scratch" || mi.mountPoint != "/mnt/scratch" || mi.fsType != "lustre" {
+		t.Errorf("unexpected result: %+v", mi)
+	}
+	if len(mi.options) != 2 || mi.options[0] != "rw" || mi.options[1] != "lazystatfs" {
+		t.Errorf("options = %v", mi.options)
+	}
+}
+
+func TestParseProcMountsLineError(t *testing.T) {
+	if _, err := parseProcMountsLine("too few fields"); err == nil {
+		t.Errorf("expected error for short line")
+	}
+}
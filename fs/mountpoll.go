@@ -0,0 +1,55 @@
+package fs
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// pollTimeoutMillis bounds how long watchMountInfo blocks in a single
+// EpollWait call, so it can periodically notice that stop was closed even
+// though it was never woken by the kernel.
+const pollTimeoutMillis = 250
+
+// watchMountInfo blocks until /proc/self/mountinfo reports a change via
+// epoll, or stop is closed. Linux marks mountinfo readable for EPOLLPRI
+// whenever the mount table changes, which lets callers avoid polling. It
+// returns true if a change was observed, or false if stop fired first.
+func watchMountInfo(stop <-chan struct{}) (bool, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	epfd, err := unix.EpollCreate1(0)
+	if err != nil {
+		return false, err
+	}
+	defer unix.Close(epfd)
+
+	ev := unix.EpollEvent{Events: unix.EPOLLPRI | unix.EPOLLERR, Fd: int32(f.Fd())}
+	if err := unix.EpollCtl(epfd, unix.EPOLL_CTL_ADD, int(f.Fd()), &ev); err != nil {
+		return false, err
+	}
+
+	events := make([]unix.EpollEvent, 1)
+	for {
+		select {
+		case <-stop:
+			return false, nil
+		default:
+		}
+
+		n, err := unix.EpollWait(epfd, events, pollTimeoutMillis)
+		if err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			return false, err
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxSymlinksResolved bounds how many symlinks secureJoin will follow while
+// resolving a path, mirroring the kernel's own MAXSYMLINKS limit so a
+// symlink loop fails with an error instead of spinning forever.
+const maxSymlinksResolved = 40
+
+// sanitizeRelPath cleans rel and rejects any path that would escape root
+// lexically. This alone isn't enough to confine rel to root - an
+// intermediate component can be a symlink to another mountpoint - so
+// callers run the result through secureJoin as well.
+func sanitizeRelPath(rel string) (string, error) {
+	clean := filepath.Clean(rel)
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("fs: path %q escapes root", rel)
+	}
+	clean = strings.TrimPrefix(clean, string(filepath.Separator))
+	if clean == "." {
+		clean = ""
+	}
+	return clean, nil
+}
+
+// rootFd returns the file descriptor of root's cached root directory,
+// opening it if necessary.
+func (root RootDir) rootFd() (int, error) {
+	return getOpenMount(root).fd()
+}
+
+// resolve sanitizes rel and then walks it component by component, the way
+// cyphar/filepath-securejoin does, following any symlinks it encounters
+// but clamping ".." and absolute symlink targets so the walk can never
+// step outside root. It returns a path, relative to root ("." for root
+// itself), that can be passed directly to the *at syscalls against root's
+// fd; unlike a purely lexical check on the unresolved rel, this keeps
+// symlinks present at resolution time from leading outside root. The check
+// is made once: a component replaced by a symlink between resolve and the
+// later syscall is not detected.
+//
+// If resolveLast is false, the final component is returned as-is without
+// being dereferenced, so callers that need to see or create the link
+// itself (LstatAt, UnlinkAt, MkdirAt, SymlinkAt, RenameAt) still can.
+func (root RootDir) resolve(rel string, resolveLast bool) (string, error) {
+	clean, err := sanitizeRelPath(rel)
+	if err != nil {
+		return "", err
+	}
+
+	var resolved string
+	remaining := clean
+	linksWalked := 0
+
+	for remaining != "" {
+		var component string
+		if i := strings.IndexByte(remaining, '/'); i < 0 {
+			component, remaining = remaining, ""
+		} else {
+			component, remaining = remaining[:i], remaining[i+1:]
+		}
+		if component == "" || component == "." {
+			continue
+		}
+		if component == ".." {
+			resolved = parentOf(resolved)
+			continue
+		}
+
+		candidate := joinRel(resolved, component)
+		if remaining == "" && !resolveLast {
+			resolved = candidate
+			break
+		}
+
+		fi, err := root.lstatRaw(candidate)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				resolved = candidate
+				continue
+			}
+			return "", err
+		}
+		if fi.Mode()&os.ModeSymlink == 0 {
+			resolved = candidate
+			continue
+		}
+
+		linksWalked++
+		if linksWalked > maxSymlinksResolved {
+			return "", fmt.Errorf("fs: too many symlinks resolving %q", rel)
+		}
+
+		target, err := root.readlinkRaw(candidate)
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(target) {
+			resolved = ""
+		}
+		remaining = strings.TrimPrefix(target+"/"+remaining, "/")
+	}
+	if resolved == "" {
+		// root itself; openat and fstatat reject an empty path.
+		resolved = "."
+	}
+	return resolved, nil
+}
+
+func parentOf(resolved string) string {
+	if i := strings.LastIndexByte(resolved, '/'); i >= 0 {
+		return resolved[:i]
+	}
+	return ""
+}
+
+func joinRel(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return dir + "/" + name
+}
+
+// OpenAt opens rel relative to root's cached root directory, rather than
+// joining and re-walking the full path, which matters for Lustre trees
+// where paths are long and a TOCTOU race across mountpoints is a real
+// concern.
+func (root RootDir) OpenAt(rel string, flags int, mode os.FileMode) (*os.File, error) {
+	clean, err := root.resolve(rel, flags&unix.O_NOFOLLOW == 0)
+	if err != nil {
+		return nil, err
+	}
+	return root.openRaw(clean, flags, mode, rel)
+}
+
+func (root RootDir) openRaw(clean string, flags int, mode os.FileMode, origRel string) (*os.File, error) {
+	dirFd, err := root.rootFd()
+	if err != nil {
+		return nil, err
+	}
+	fd, err := unix.Openat(dirFd, clean, flags, uint32(mode))
+	if err != nil {
+		return nil, &os.PathError{Op: "openat", Path: root.Join(origRel), Err: err}
+	}
+	return os.NewFile(uintptr(fd), root.Join(origRel)), nil
+}
+
+// statRaw/lstatRaw stat clean (already sanitized, already resolved)
+// relative to root's cached root fd, converting the raw unix.Stat_t
+// ourselves rather than relying on a /proc/self/fd lstat - lstat on a
+// procfs fd symlink reports the magic symlink's own stat, not the target's,
+// so that shortcut silently reports every file as a symlink.
+func (root RootDir) statRaw(clean string, flags int, origRel string) (os.FileInfo, error) {
+	dirFd, err := root.rootFd()
+	if err != nil {
+		return nil, err
+	}
+	var stat unix.Stat_t
+	if err := unix.Fstatat(dirFd, clean, &stat, flags); err != nil {
+		return nil, &os.PathError{Op: "statat", Path: root.Join(origRel), Err: err}
+	}
+	name := clean
+	if i := strings.LastIndexByte(clean, '/'); i >= 0 {
+		name = clean[i+1:]
+	}
+	return &statAtFileInfo{name: name, stat: stat}, nil
+}
+
+// lstatRaw is statRaw without following a trailing symlink, used internally
+// by resolve to decide whether a component needs dereferencing.
+func (root RootDir) lstatRaw(clean string) (os.FileInfo, error) {
+	return root.statRaw(clean, unix.AT_SYMLINK_NOFOLLOW, clean)
+}
+
+func (root RootDir) readlinkRaw(clean string) (string, error) {
+	dirFd, err := root.rootFd()
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, 256)
+	for {
+		n, err := unix.Readlinkat(dirFd, clean, buf)
+		if err != nil {
+			return "", &os.PathError{Op: "readlinkat", Path: root.Join(clean), Err: err}
+		}
+		if n < len(buf) {
+			return string(buf[:n]), nil
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+}
+
+// StatAt returns file info for rel, relative to root, following a trailing
+// symlink.
+func (root RootDir) StatAt(rel string) (os.FileInfo, error) {
+	clean, err := root.resolve(rel, true)
+	if err != nil {
+		return nil, err
+	}
+	return root.statRaw(clean, 0, rel)
+}
+
+// LstatAt returns file info for rel, relative to root, without following a
+// trailing symlink.
+func (root RootDir) LstatAt(rel string) (os.FileInfo, error) {
+	clean, err := root.resolve(rel, false)
+	if err != nil {
+		return nil, err
+	}
+	return root.statRaw(clean, unix.AT_SYMLINK_NOFOLLOW, rel)
+}
+
+// MkdirAt creates rel, relative to root, with the given permission bits.
+func (root RootDir) MkdirAt(rel string, mode os.FileMode) error {
+	clean, err := root.resolve(rel, false)
+	if err != nil {
+		return err
+	}
+	dirFd, err := root.rootFd()
+	if err != nil {
+		return err
+	}
+	if err := unix.Mkdirat(dirFd, clean, uint32(mode)); err != nil {
+		return &os.PathError{Op: "mkdirat", Path: root.Join(rel), Err: err}
+	}
+	return nil
+}
+
+// UnlinkAt removes rel, relative to root. It calls unlinkat without
+// AT_REMOVEDIR, so it does not remove directories.
+func (root RootDir) UnlinkAt(rel string) error {
+	clean, err := root.resolve(rel, false)
+	if err != nil {
+		return err
+	}
+	dirFd, err := root.rootFd()
+	if err != nil {
+		return err
+	}
+	if err := unix.Unlinkat(dirFd, clean, 0); err != nil {
+		return &os.PathError{Op: "unlinkat", Path: root.Join(rel), Err: err}
+	}
+	return nil
+}
+
+// RenameAt renames oldRel to newRel, both relative to root.
+func (root RootDir) RenameAt(oldRel, newRel string) error {
+	oldClean, err := root.resolve(oldRel, false)
+	if err != nil {
+		return err
+	}
+	newClean, err := root.resolve(newRel, false)
+	if err != nil {
+		return err
+	}
+	dirFd, err := root.rootFd()
+	if err != nil {
+		return err
+	}
+	if err := unix.Renameat(dirFd, oldClean, dirFd, newClean); err != nil {
+		return &os.LinkError{Op: "renameat", Old: root.Join(oldRel), New: root.Join(newRel), Err: err}
+	}
+	return nil
+}
+
+// SymlinkAt creates newRel, relative to root, as a symlink to oldname.
+// oldname is stored verbatim and is not resolved against root.
+func (root RootDir) SymlinkAt(oldname, newRel string) error {
+	newClean, err := root.resolve(newRel, false)
+	if err != nil {
+		return err
+	}
+	dirFd, err := root.rootFd()
+	if err != nil {
+		return err
+	}
+	if err := unix.Symlinkat(oldname, dirFd, newClean); err != nil {
+		return &os.LinkError{Op: "symlinkat", Old: oldname, New: root.Join(newRel), Err: err}
+	}
+	return nil
+}
+
+// ReadDirAt reads the directory entries of rel, relative to root.
+func (root RootDir) ReadDirAt(rel string) ([]os.DirEntry, error) {
+	f, err := root.OpenAt(rel, unix.O_RDONLY|unix.O_DIRECTORY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.ReadDir(-1)
+}
+
+// statAtFileInfo implements os.FileInfo over a raw unix.Stat_t, since
+// Fstatat gives us that rather than anything os already knows how to wrap.
+type statAtFileInfo struct {
+	name string
+	stat unix.Stat_t
+}
+
+func (fi *statAtFileInfo) Name() string       { return fi.name }
+func (fi *statAtFileInfo) Size() int64        { return fi.stat.Size }
+func (fi *statAtFileInfo) Mode() os.FileMode  { return unixFileMode(fi.stat.Mode) }
+func (fi *statAtFileInfo) ModTime() time.Time { return time.Unix(fi.stat.Mtim.Unix()) }
+func (fi *statAtFileInfo) IsDir() bool        { return fi.Mode().IsDir() }
+func (fi *statAtFileInfo) Sys() interface{}   { return &fi.stat }
+
+// unixFileMode converts a raw st_mode from stat(2) into an os.FileMode,
+// the same bits os.Lstat derives from syscall.Stat_t internally.
+func unixFileMode(m uint32) os.FileMode {
+	mode := os.FileMode(m & 0777)
+	switch m & unix.S_IFMT {
+	case unix.S_IFBLK:
+		mode |= os.ModeDevice
+	case unix.S_IFCHR:
+		mode |= os.ModeDevice | os.ModeCharDevice
+	case unix.S_IFDIR:
+		mode |= os.ModeDir
+	case unix.S_IFIFO:
+		mode |= os.ModeNamedPipe
+	case unix.S_IFLNK:
+		mode |= os.ModeSymlink
+	case unix.S_IFSOCK:
+		mode |= os.ModeSocket
+	}
+	if m&unix.S_ISGID != 0 {
+		mode |= os.ModeSetgid
+	}
+	if m&unix.S_ISUID != 0 {
+		mode |= os.ModeSetuid
+	}
+	if m&unix.S_ISVTX != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
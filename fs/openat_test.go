@@ -0,0 +1,136 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSanitizeRelPath(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "a/b", want: "a/b"},
+		{in: "./a/./b", want: "a/b"},
+		{in: "a/../b", want: "b"},
+		{in: "", want: ""},
+		{in: ".", want: ""},
+		{in: "/etc/passwd", want: "etc/passwd"},
+		{in: "..", wantErr: true},
+		{in: "../etc/passwd", wantErr: true},
+		{in: "a/../../etc", wantErr: true},
+	}
+	for _, c := range cases {
+		got, err := sanitizeRelPath(c.in)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("sanitizeRelPath(%q): expected error, got %q", c.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("sanitizeRelPath(%q): unexpected error: %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("sanitizeRelPath(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+// TestRootDirOpenAtFollowsInScopeSymlink verifies legitimate, in-root
+// symlinks still resolve and open correctly.
+func TestRootDirOpenAtFollowsInScopeSymlink(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "real"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("real", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	root := RootDir(dir)
+	f, err := root.OpenAt("link", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenAt(link): %v", err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, 5)
+	if _, err := f.Read(buf); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if string(buf) != "hello" {
+		t.Errorf("content = %q, want hello", buf)
+	}
+}
+
+// TestRootDirOpenAtRejectsSymlinkEscape verifies that a symlink pointing
+// outside root can't be used to read a file outside the tree, even though
+// the rel string itself never mentions "..".
+func TestRootDirOpenAtRejectsSymlinkEscape(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "secret")
+	if err := os.WriteFile(secret, []byte("private"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	root := RootDir(dir)
+	f, err := root.OpenAt("escape/secret", os.O_RDONLY, 0)
+	if err == nil {
+		f.Close()
+		t.Fatalf("OpenAt(escape/secret): expected error, symlink should have been confined to root")
+	}
+}
+
+func TestRootDirLstatAtReportsSymlinkItself(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "real"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("real", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	root := RootDir(dir)
+	fi, err := root.LstatAt("link")
+	if err != nil {
+		t.Fatalf("LstatAt: %v", err)
+	}
+	if fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Mode() = %v, want ModeSymlink set", fi.Mode())
+	}
+
+	fi, err = root.StatAt("link")
+	if err != nil {
+		t.Fatalf("StatAt: %v", err)
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		t.Errorf("StatAt followed mode = %v, want symlink bit cleared", fi.Mode())
+	}
+	if fi.IsDir() {
+		t.Errorf("IsDir() = true for a regular file target")
+	}
+}
+
+func TestRootDirStatAtReportsDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "subdir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	root := RootDir(dir)
+	fi, err := root.StatAt("subdir")
+	if err != nil {
+		t.Fatalf("StatAt: %v", err)
+	}
+	if !fi.IsDir() {
+		t.Errorf("IsDir() = false, want true for a directory")
+	}
+}
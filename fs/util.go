@@ -10,6 +10,8 @@ import (
 	"sync"
 	"syscall"
 
+	"golang.org/x/sys/unix"
+
 	"github.intel.com/hpdd/lustre"
 	"github.intel.com/hpdd/lustre/llapi"
 	"github.intel.com/hpdd/lustre/status"
@@ -50,7 +52,10 @@ type mountDir struct {
 
 // A cache of file handles per lustre mount point. Currently used to fetch the host Mdt for a file.
 // Could merge with RootDir and ensure RootDir is a singleton per client
-var openMount map[RootDir]*mountDir
+var (
+	openMountLock sync.Mutex
+	openMount     map[RootDir]*mountDir
+)
 
 func init() {
 	openMount = make(map[RootDir]*mountDir)
@@ -89,8 +94,22 @@ func (m *mountDir) GetMdt(in *lustre.Fid) (int, error) {
 	return mdtIndex, nil
 }
 
+// fd returns the file descriptor of root's cached open directory handle,
+// opening it if necessary. This is the source of truth the RootDir *At
+// methods resolve against.
+func (m *mountDir) fd() (int, error) {
+	if !m.opened {
+		if err := m.open(); err != nil {
+			return -1, err
+		}
+	}
+	return int(m.f.Fd()), nil
+}
+
 func getOpenMount(root RootDir) *mountDir {
-	//	var mnt *mountDir
+	openMountLock.Lock()
+	defer openMountLock.Unlock()
+
 	mnt, ok := openMount[root]
 	if !ok {
 		mnt = &mountDir{path: root}
@@ -106,7 +125,10 @@ func GetMdt(root RootDir, f *lustre.Fid) (int, error) {
 }
 
 // Join args with root dir to create an absolute path.
-// FIXME: replace this with OpenAt and friends
+//
+// Deprecated: building and re-walking an absolute path is racy on Lustre
+// trees with long paths and submounts. Use OpenAt, StatAt, and friends
+// instead, which resolve against root's cached file descriptor.
 func (root RootDir) Join(args ...string) string {
 	return path.Join(string(root), path.Join(args...))
 }
@@ -142,85 +164,19 @@ func GetID(p string) (ID, error) {
 	return ID(r), nil
 }
 
-// Determine if given directory is the one true magical DOT_LUSTRE directory.
-func isDotLustre(dir string) bool {
-	fi, err := os.Lstat(dir)
-	if err != nil {
-		return false
-	}
-	if fi.IsDir() {
-		fid, err := LookupFid(dir)
-		if err == nil && fid.IsDotLustre() {
-			return true
-		}
-	}
-	return false
-}
-
-// Return root device from the struct stat embedded in FileInfo
-func rootDevice(fi os.FileInfo) uint64 {
-	stat, ok := fi.Sys().(*syscall.Stat_t)
-	if ok {
-		return stat.Dev
-	}
-	panic("no stat available")
-}
-
-// findRoot returns the root directory for the lustre filesystem containing
-// the pathname. If the the filesystem is not lustre, then error is returned.
-func findRoot(dev uint64, pathname string) string {
-	parent := path.Dir(pathname)
-	fi, err := os.Lstat(parent)
-	if err != nil {
-		return ""
-	}
-	//  If "/" is lustre then we won't see the device change
-	if rootDevice(fi) != dev || pathname == "/" {
-		if isDotLustre(path.Join(pathname, ".lustre")) {
-			return pathname
-		}
-		return ""
-	}
-
-	return findRoot(dev, parent)
-}
-
 // MountRoot returns the Lustre filesystem mountpoint for the give path
 // or returns an error if the path is not on a Lustre filesystem.
-func MountRoot(path string) (RootDir, error) {
-	fi, err := os.Lstat(path)
-	if err != nil {
-		return RootDir(""), err
-	}
-
-	mnt := findRoot(rootDevice(fi), path)
-	if mnt == "" {
-		return RootDir(""), fmt.Errorf("%s not a Lustre filesystem", path)
-	}
-	return RootDir(mnt), nil
-}
-
-// findRelPah returns pathname relative to root directory for the lustre filesystem containing
-// the pathname. If no Lustre root was found, then empty strings are returned.
-func findRelPath(dev uint64, pathname string, relPath []string) (string, string) {
-	parent := path.Dir(pathname)
-	fi, err := os.Lstat(parent)
-	if err != nil {
-		return "", ""
-	}
-	//  If "/" is lustre then we won't see the device change
-	if rootDevice(fi) != dev || pathname == "/" {
-		if isDotLustre(path.Join(pathname, ".lustre")) {
-			return pathname, path.Join(relPath...)
-		}
-		return "", ""
-	}
-
-	return findRelPath(dev, parent, append([]string{path.Base(pathname)}, relPath...))
+func MountRoot(pathname string) (RootDir, error) {
+	root, _, err := MountRelPath(pathname)
+	return root, err
 }
 
 // MountRelPath returns the lustre mountpoint, and remaing path for the given pathname. The remaining  paht
 // is relative to the mount point. Returns an error if pathname is not valid or does not refer to a Lustre fs.
+//
+// This is answered from a cached, longest-prefix lookup of the host's mount
+// table rather than by walking up pathname's parents doing an Lstat at each
+// level; see mountCache for details.
 func MountRelPath(pathname string) (RootDir, string, error) {
 	pathname = filepath.Clean(pathname)
 	fi, err := os.Lstat(pathname)
@@ -228,9 +184,19 @@ func MountRelPath(pathname string) (RootDir, string, error) {
 		return RootDir(""), "", err
 	}
 
-	root, relPath := findRelPath(rootDevice(fi), pathname, []string{})
-	if root == "" {
-		return RootDir(""), "", fmt.Errorf("%s not a Lustre filesystem", pathname)
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return RootDir(""), "", fmt.Errorf("fs: no stat available for %s", pathname)
 	}
-	return RootDir(root), relPath, nil
-}
\ No newline at end of file
+
+	if err := gMountCache.ensureBuilt(); err != nil {
+		return RootDir(""), "", err
+	}
+
+	major, minor := unix.Major(stat.Dev), unix.Minor(stat.Dev)
+	root, rel, ok := gMountCache.lookup(pathname, uint64(major), uint64(minor))
+	if !ok {
+		return RootDir(""), "", fmt.Errorf("%s: %w", pathname, ErrNotLustre)
+	}
+	return root, rel, nil
+}
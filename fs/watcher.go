@@ -0,0 +1,164 @@
+package fs
+
+import (
+	"fmt"
+	"time"
+)
+
+// pollFallbackInterval is how often MountWatcher re-checks the mount table
+// when /proc/self/mountinfo can't be watched via epoll.
+const pollFallbackInterval = 2 * time.Second
+
+// MountEventType describes whether a MountEvent is a filesystem being
+// mounted or unmounted.
+type MountEventType int
+
+const (
+	// Added indicates a Lustre filesystem was newly mounted.
+	Added MountEventType = iota
+	// Removed indicates a previously mounted Lustre filesystem went away.
+	Removed
+)
+
+func (t MountEventType) String() string {
+	switch t {
+	case Added:
+		return "Added"
+	case Removed:
+		return "Removed"
+	default:
+		return "Unknown"
+	}
+}
+
+// MountEvent reports a Lustre filesystem being mounted or unmounted on the
+// local host.
+type MountEvent struct {
+	Type  MountEventType
+	Entry MountEntry
+}
+
+// MountWatcher watches the host's mount table and emits a MountEvent
+// whenever a Lustre filesystem is mounted or unmounted. This lets
+// long-lived daemons (copytools, HSM agents) react to clients coming and
+// going without polling GetLustreMounts themselves.
+type MountWatcher struct {
+	events chan MountEvent
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewMountWatcher starts watching for Lustre filesystems being mounted or
+// unmounted on the host. Call Close when done to stop the watcher.
+func NewMountWatcher() (*MountWatcher, error) {
+	known, err := GetLustreMounts()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &MountWatcher{
+		events: make(chan MountEvent),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go w.run(mountEntrySet(known))
+	return w, nil
+}
+
+// Events returns the channel MountEvents are delivered on. It is closed
+// after Close has stopped the watcher.
+func (w *MountWatcher) Events() <-chan MountEvent {
+	return w.events
+}
+
+// Close stops the watcher and waits for its goroutine to exit. It is safe
+// to call more than once.
+func (w *MountWatcher) Close() error {
+	select {
+	case <-w.stop:
+	default:
+		close(w.stop)
+	}
+	<-w.done
+	return nil
+}
+
+func (w *MountWatcher) run(known map[string]MountEntry) {
+	defer close(w.done)
+	defer close(w.events)
+
+	useEpoll := true
+	for {
+		if useEpoll {
+			changed, err := watchMountInfo(w.stop)
+			if err != nil {
+				// Epoll on mountinfo isn't available on this kernel or
+				// platform; fall back to polling instead.
+				useEpoll = false
+				continue
+			}
+			if !changed {
+				return // w.stop was closed
+			}
+		} else {
+			select {
+			case <-w.stop:
+				return
+			case <-time.After(pollFallbackInterval):
+			}
+		}
+
+		current, err := GetLustreMounts()
+		if err != nil {
+			continue
+		}
+		currentSet := mountEntrySet(current)
+
+		for _, ev := range diffMounts(known, currentSet) {
+			if !w.send(ev) {
+				return
+			}
+		}
+		known = currentSet
+	}
+}
+
+func (w *MountWatcher) send(ev MountEvent) bool {
+	select {
+	case w.events <- ev:
+		return true
+	case <-w.stop:
+		return false
+	}
+}
+
+// mountEntrySet keys entries by mountpoint, filesystem name, and client ID,
+// which is how MountWatcher identifies "the same" mount across two
+// snapshots of the mount table.
+func mountEntrySet(entries []MountEntry) map[string]MountEntry {
+	set := make(map[string]MountEntry, len(entries))
+	for _, e := range entries {
+		key := fmt.Sprintf("%s|%s|%s", e.RootDir, e.FsName, e.ClientID)
+		set[key] = e
+	}
+	return set
+}
+
+// diffMounts compares two mountEntrySet snapshots and returns the events
+// needed to bring known up to date with current: an Added event for every
+// entry in current that wasn't in known, and a Removed event for every
+// entry in known that's no longer in current.
+func diffMounts(known, current map[string]MountEntry) []MountEvent {
+	var events []MountEvent
+	for key, entry := range current {
+		if _, ok := known[key]; !ok {
+			events = append(events, MountEvent{Type: Added, Entry: entry})
+		}
+	}
+	for key, entry := range known {
+		if _, ok := current[key]; !ok {
+			events = append(events, MountEvent{Type: Removed, Entry: entry})
+		}
+	}
+	return events
+}
@@ -0,0 +1,109 @@
+package fs
+
+import "testing"
+
+func TestMountEntrySetKeysByMountFsNameClientID(t *testing.T) {
+	entries := []MountEntry{
+		{RootDir: RootDir("/mnt/scratch"), FsName: "scratch", ClientID: "abcd"},
+		{RootDir: RootDir("/mnt/work"), FsName: "work", ClientID: "ef01"},
+	}
+	set := mountEntrySet(entries)
+	if len(set) != 2 {
+		t.Fatalf("len(set) = %d, want 2", len(set))
+	}
+	if _, ok := set["/mnt/scratch|scratch|abcd"]; !ok {
+		t.Errorf("set missing key for scratch entry: %+v", set)
+	}
+	if _, ok := set["/mnt/work|work|ef01"]; !ok {
+		t.Errorf("set missing key for work entry: %+v", set)
+	}
+}
+
+func TestMountEntrySetCollapsesDuplicates(t *testing.T) {
+	entries := []MountEntry{
+		{RootDir: RootDir("/mnt/scratch"), FsName: "scratch", ClientID: "abcd"},
+		{RootDir: RootDir("/mnt/scratch"), FsName: "scratch", ClientID: "abcd"},
+	}
+	set := mountEntrySet(entries)
+	if len(set) != 1 {
+		t.Errorf("len(set) = %d, want 1", len(set))
+	}
+}
+
+func TestDiffMountsAdded(t *testing.T) {
+	known := mountEntrySet(nil)
+	current := mountEntrySet([]MountEntry{
+		{RootDir: RootDir("/mnt/scratch"), FsName: "scratch", ClientID: "abcd"},
+	})
+
+	events := diffMounts(known, current)
+	if len(events) != 1 {
+		t.Fatalf("len(events) = %d, want 1", len(events))
+	}
+	if events[0].Type != Added {
+		t.Errorf("Type = %v, want Added", events[0].Type)
+	}
+	if events[0].Entry.RootDir != RootDir("/mnt/scratch") {
+		t.Errorf("Entry.RootDir = %v, want /mnt/scratch", events[0].Entry.RootDir)
+	}
+}
+
+func TestDiffMountsRemoved(t *testing.T) {
+	known := mountEntrySet([]MountEntry{
+		{RootDir: RootDir("/mnt/scratch"), FsName: "scratch", ClientID: "abcd"},
+	})
+	current := mountEntrySet(nil)
+
+	events := diffMounts(known, current)
+	if len(events) != 1 {
+		t.Fatalf("len(events) = %d, want 1", len(events))
+	}
+	if events[0].Type != Removed {
+		t.Errorf("Type = %v, want Removed", events[0].Type)
+	}
+}
+
+func TestDiffMountsUnchanged(t *testing.T) {
+	entries := []MountEntry{
+		{RootDir: RootDir("/mnt/scratch"), FsName: "scratch", ClientID: "abcd"},
+	}
+	known := mountEntrySet(entries)
+	current := mountEntrySet(entries)
+
+	if events := diffMounts(known, current); len(events) != 0 {
+		t.Errorf("diffMounts(unchanged) = %+v, want no events", events)
+	}
+}
+
+func TestDiffMountsAddedAndRemoved(t *testing.T) {
+	known := mountEntrySet([]MountEntry{
+		{RootDir: RootDir("/mnt/old"), FsName: "old", ClientID: "aaaa"},
+	})
+	current := mountEntrySet([]MountEntry{
+		{RootDir: RootDir("/mnt/new"), FsName: "new", ClientID: "bbbb"},
+	})
+
+	events := diffMounts(known, current)
+	if len(events) != 2 {
+		t.Fatalf("len(events) = %d, want 2", len(events))
+	}
+
+	var sawAdded, sawRemoved bool
+	for _, ev := range events {
+		switch ev.Type {
+		case Added:
+			sawAdded = true
+			if ev.Entry.RootDir != RootDir("/mnt/new") {
+				t.Errorf("Added entry = %+v, want /mnt/new", ev.Entry)
+			}
+		case Removed:
+			sawRemoved = true
+			if ev.Entry.RootDir != RootDir("/mnt/old") {
+				t.Errorf("Removed entry = %+v, want /mnt/old", ev.Entry)
+			}
+		}
+	}
+	if !sawAdded || !sawRemoved {
+		t.Errorf("events = %+v, want one Added and one Removed", events)
+	}
+}
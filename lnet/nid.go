@@ -0,0 +1,230 @@
+package lnet
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Nid is a parsed Lustre network identifier, in the canonical
+// "address@driverN" form (e.g. "10.0.0.1@tcp0").
+type Nid interface {
+	// Address is the driver-specific address: *net.IP for tcp/o2ib, a
+	// node id for gni, or the raw string for drivers we don't understand.
+	Address() interface{}
+
+	// Driver is the LNet driver name, e.g. "tcp", "o2ib", "gni", "lo".
+	Driver() string
+
+	// LNet is the driver plus its instance number, e.g. "tcp0", "o2ib1".
+	LNet() string
+}
+
+// O2ibNid is an InfiniBand NID: an IPv4 address plus an o2ib driver
+// instance (e.g. "192.168.7.4@o2ib1").
+type O2ibNid struct {
+	IPAddress      *net.IP
+	driverInstance int
+}
+
+func (n *O2ibNid) Address() interface{} {
+	return n.IPAddress
+}
+
+func (n *O2ibNid) Driver() string {
+	return "o2ib"
+}
+
+func (n *O2ibNid) LNet() string {
+	return fmt.Sprintf("%s%d", n.Driver(), n.driverInstance)
+}
+
+func newO2ibNid(address string, driverInstance int) (*O2ibNid, error) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil, fmt.Errorf("%q is not a valid IP address", address)
+	}
+	return &O2ibNid{
+		IPAddress:      &ip,
+		driverInstance: driverInstance,
+	}, nil
+}
+
+// GniNid is a Cray Gemini/Aries NID, addressed by an integer node id (e.g.
+// "4@gni").
+type GniNid struct {
+	NodeID         uint32
+	driverInstance int
+}
+
+func (n *GniNid) Address() interface{} {
+	return n.NodeID
+}
+
+func (n *GniNid) Driver() string {
+	return "gni"
+}
+
+func (n *GniNid) LNet() string {
+	return fmt.Sprintf("%s%d", n.Driver(), n.driverInstance)
+}
+
+func newGniNid(address string, driverInstance int) (*GniNid, error) {
+	id, err := strconv.ParseUint(address, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a valid gni node id", address)
+	}
+	return &GniNid{
+		NodeID:         uint32(id),
+		driverInstance: driverInstance,
+	}, nil
+}
+
+// PtlNid is a Portals NID, addressed by an integer node id (e.g. "2@ptl").
+type PtlNid struct {
+	NodeID         uint32
+	driverInstance int
+}
+
+func (n *PtlNid) Address() interface{} {
+	return n.NodeID
+}
+
+func (n *PtlNid) Driver() string {
+	return "ptl"
+}
+
+func (n *PtlNid) LNet() string {
+	return fmt.Sprintf("%s%d", n.Driver(), n.driverInstance)
+}
+
+func newPtlNid(address string, driverInstance int) (*PtlNid, error) {
+	id, err := strconv.ParseUint(address, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a valid ptl node id", address)
+	}
+	return &PtlNid{
+		NodeID:         uint32(id),
+		driverInstance: driverInstance,
+	}, nil
+}
+
+// LoNid is the loopback NID, "0@lo".
+type LoNid struct{}
+
+func (n *LoNid) Address() interface{} {
+	return nil
+}
+
+func (n *LoNid) Driver() string {
+	return "lo"
+}
+
+func (n *LoNid) LNet() string {
+	return "lo"
+}
+
+// GenericNid is a fallback Nid for drivers we don't otherwise parse. It
+// preserves the original string so callers can still display or re-mount
+// with it.
+type GenericNid struct {
+	raw            string
+	driver         string
+	driverInstance int
+}
+
+func (n *GenericNid) Address() interface{} {
+	return n.raw
+}
+
+func (n *GenericNid) Driver() string {
+	return n.driver
+}
+
+func (n *GenericNid) LNet() string {
+	return fmt.Sprintf("%s%d", n.driver, n.driverInstance)
+}
+
+// splitDriverInstance splits a "driverN" string, such as "tcp0" or "gni",
+// into its driver name and trailing instance number (0 if absent).
+func splitDriverInstance(s string) (driver string, instance int, err error) {
+	i := len(s)
+	for i > 0 && s[i-1] >= '0' && s[i-1] <= '9' {
+		i--
+	}
+	driver = s[:i]
+	if driver == "" {
+		return "", 0, fmt.Errorf("invalid driver %q", s)
+	}
+	if i == len(s) {
+		return driver, 0, nil
+	}
+	instance, err = strconv.Atoi(s[i:])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid driver instance in %q", s)
+	}
+	return driver, instance, nil
+}
+
+// ParseNID parses a Lustre NID in the canonical "address@driverN" form,
+// e.g. "10.0.0.1@tcp0", "192.168.7.4@o2ib1", "4@gni", or "0@lo".
+func ParseNID(s string) (Nid, error) {
+	at := strings.LastIndex(s, "@")
+	if at < 0 {
+		return nil, fmt.Errorf("lnet: invalid NID %q: missing \"@driver\"", s)
+	}
+	address, driverPart := s[:at], s[at+1:]
+
+	driver, instance, err := splitDriverInstance(driverPart)
+	if err != nil {
+		return nil, fmt.Errorf("lnet: invalid NID %q: %v", s, err)
+	}
+
+	switch driver {
+	case "tcp":
+		return newTcpNid(address, instance)
+	case "o2ib":
+		return newO2ibNid(address, instance)
+	case "gni":
+		return newGniNid(address, instance)
+	case "ptl":
+		return newPtlNid(address, instance)
+	case "lo":
+		return &LoNid{}, nil
+	default:
+		return &GenericNid{raw: s, driver: driver, driverInstance: instance}, nil
+	}
+}
+
+// ParseMGSSpec parses a full Lustre mount source of the form
+// "nid1,nid2:nid3,nid4:/fsname", as found in fstab entries and
+// /proc/self/mountinfo. Colons separate MGS servers; commas separate
+// failover NIDs for the same server. The returned slice has one entry per
+// server, in order, each holding that server's failover NIDs. The trailing
+// "/fsname", if present, is returned separately with the colon consumed.
+func ParseMGSSpec(s string) (nids [][]Nid, fsName string, err error) {
+	if idx := strings.LastIndex(s, ":/"); idx >= 0 {
+		fsName = s[idx+2:]
+		s = s[:idx]
+	}
+
+	for _, server := range strings.Split(s, ":") {
+		if server == "" {
+			continue
+		}
+		var group []Nid
+		for _, raw := range strings.Split(server, ",") {
+			if raw == "" {
+				continue
+			}
+			nid, err := ParseNID(raw)
+			if err != nil {
+				return nil, "", err
+			}
+			group = append(group, nid)
+		}
+		nids = append(nids, group)
+	}
+	return nids, fsName, nil
+}
@@ -0,0 +1,167 @@
+package lnet
+
+import "testing"
+
+func TestParseNIDTcp(t *testing.T) {
+	nid, err := ParseNID("10.0.0.1@tcp0")
+	if err != nil {
+		t.Fatalf("ParseNID: %v", err)
+	}
+	tcp, ok := nid.(*TcpNid)
+	if !ok {
+		t.Fatalf("ParseNID returned %T, want *TcpNid", nid)
+	}
+	if tcp.Driver() != "tcp" || tcp.LNet() != "tcp0" {
+		t.Errorf("Driver/LNet = %q/%q, want tcp/tcp0", tcp.Driver(), tcp.LNet())
+	}
+	if tcp.IPAddress.String() != "10.0.0.1" {
+		t.Errorf("Address = %v, want 10.0.0.1", tcp.IPAddress)
+	}
+}
+
+func TestParseNIDO2ib(t *testing.T) {
+	nid, err := ParseNID("192.168.7.4@o2ib1")
+	if err != nil {
+		t.Fatalf("ParseNID: %v", err)
+	}
+	o2ib, ok := nid.(*O2ibNid)
+	if !ok {
+		t.Fatalf("ParseNID returned %T, want *O2ibNid", nid)
+	}
+	if o2ib.LNet() != "o2ib1" {
+		t.Errorf("LNet() = %q, want o2ib1", o2ib.LNet())
+	}
+}
+
+func TestParseNIDGni(t *testing.T) {
+	nid, err := ParseNID("4@gni")
+	if err != nil {
+		t.Fatalf("ParseNID: %v", err)
+	}
+	gni, ok := nid.(*GniNid)
+	if !ok {
+		t.Fatalf("ParseNID returned %T, want *GniNid", nid)
+	}
+	if gni.NodeID != 4 {
+		t.Errorf("NodeID = %d, want 4", gni.NodeID)
+	}
+	if gni.LNet() != "gni0" {
+		t.Errorf("LNet() = %q, want gni0", gni.LNet())
+	}
+}
+
+func TestParseNIDLo(t *testing.T) {
+	nid, err := ParseNID("0@lo")
+	if err != nil {
+		t.Fatalf("ParseNID: %v", err)
+	}
+	if _, ok := nid.(*LoNid); !ok {
+		t.Fatalf("ParseNID returned %T, want *LoNid", nid)
+	}
+	if nid.Driver() != "lo" || nid.LNet() != "lo" {
+		t.Errorf("Driver/LNet = %q/%q, want lo/lo", nid.Driver(), nid.LNet())
+	}
+}
+
+func TestParseNIDGenericFallback(t *testing.T) {
+	nid, err := ParseNID("foo@newdriver3")
+	if err != nil {
+		t.Fatalf("ParseNID: %v", err)
+	}
+	generic, ok := nid.(*GenericNid)
+	if !ok {
+		t.Fatalf("ParseNID returned %T, want *GenericNid", nid)
+	}
+	if generic.Driver() != "newdriver" || generic.LNet() != "newdriver3" {
+		t.Errorf("Driver/LNet = %q/%q, want newdriver/newdriver3", generic.Driver(), generic.LNet())
+	}
+	if generic.Address() != "foo@newdriver3" {
+		t.Errorf("Address() = %v, want raw string", generic.Address())
+	}
+}
+
+func TestParseNIDErrors(t *testing.T) {
+	cases := []string{
+		"",
+		"10.0.0.1",       // missing "@driver"
+		"notanip@tcp0",   // bad tcp address
+		"notanip@o2ib1",  // bad o2ib address
+		"notanumber@gni", // bad gni node id
+		"1.2.3.4@",       // empty driver
+	}
+	for _, s := range cases {
+		if _, err := ParseNID(s); err == nil {
+			t.Errorf("ParseNID(%q): expected error, got nil", s)
+		}
+	}
+}
+
+func TestSplitDriverInstance(t *testing.T) {
+	cases := []struct {
+		in       string
+		driver   string
+		instance int
+	}{
+		{"tcp0", "tcp", 0},
+		{"tcp12", "tcp", 12},
+		{"gni", "gni", 0},
+		{"o2ib1", "o2ib", 1},
+	}
+	for _, c := range cases {
+		driver, instance, err := splitDriverInstance(c.in)
+		if err != nil {
+			t.Errorf("splitDriverInstance(%q): %v", c.in, err)
+			continue
+		}
+		if driver != c.driver || instance != c.instance {
+			t.Errorf("splitDriverInstance(%q) = (%q, %d), want (%q, %d)", c.in, driver, instance, c.driver, c.instance)
+		}
+	}
+}
+
+func TestSplitDriverInstanceError(t *testing.T) {
+	if _, _, err := splitDriverInstance(""); err == nil {
+		t.Errorf("splitDriverInstance(\"\"): expected error")
+	}
+}
+
+func TestParseMGSSpec(t *testing.T) {
+	groups, fsName, err := ParseMGSSpec("10.0.0.1@tcp0,10.0.0.2@tcp0:10.0.0.3@tcp0:/scratch")
+	if err != nil {
+		t.Fatalf("ParseMGSSpec: %v", err)
+	}
+	if fsName != "scratch" {
+		t.Errorf("fsName = %q, want scratch", fsName)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("len(groups) = %d, want 2", len(groups))
+	}
+	if len(groups[0]) != 2 {
+		t.Errorf("len(groups[0]) = %d, want 2 failover NIDs", len(groups[0]))
+	}
+	if len(groups[1]) != 1 {
+		t.Errorf("len(groups[1]) = %d, want 1 NID", len(groups[1]))
+	}
+	if groups[0][0].LNet() != "tcp0" || groups[0][1].LNet() != "tcp0" {
+		t.Errorf("unexpected NIDs in first group: %+v", groups[0])
+	}
+}
+
+func TestParseMGSSpecNoFsName(t *testing.T) {
+	groups, fsName, err := ParseMGSSpec("10.0.0.1@tcp0")
+	if err != nil {
+		t.Fatalf("ParseMGSSpec: %v", err)
+	}
+	if fsName != "" {
+		t.Errorf("fsName = %q, want empty", fsName)
+	}
+	if len(groups) != 1 || len(groups[0]) != 1 {
+		t.Errorf("unexpected groups: %+v", groups)
+	}
+}
+
+func TestParseMGSSpecError(t *testing.T) {
+	if _, _, err := ParseMGSSpec("notanip@tcp0:/scratch"); err == nil {
+		t.Errorf("ParseMGSSpec: expected error for invalid NID")
+	}
+}